@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewExportEPUBCommand returns the `portfoliodb export epub` subcommand, which renders the
+// built database to one .epub file per exported language using BuildEPUB.
+func NewExportEPUBCommand() *cobra.Command {
+	var language string
+	var coverPath string
+	var stylesheetPath string
+	var outputPath string
+
+	cmd := &cobra.Command{
+		Use:   "epub",
+		Short: "Export the portfolio database to EPUB",
+		Long:  "Renders the built database to a single EPUB file, one chapter per work, for the given language (or every language, one file each, when --language is omitted).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// loadBuiltDatabase hooks into the database-building command's own flags
+			// (source directory, config, ...) to get the already-built []Work; it's
+			// shared with the other `portfoliodb export` subcommands.
+			db, err := loadBuiltDatabase(cmd)
+			if err != nil {
+				return err
+			}
+
+			opts := EPUBOptions{}
+			if coverPath != "" {
+				opts.CoverImage, err = os.ReadFile(coverPath)
+				if err != nil {
+					return err
+				}
+				opts.CoverMIME = mimeTypeFromExtension(coverPath)
+			}
+			if stylesheetPath != "" {
+				stylesheet, err := os.ReadFile(stylesheetPath)
+				if err != nil {
+					return err
+				}
+				opts.Stylesheet = string(stylesheet)
+			}
+
+			// BuildEPUB produces a single-language archive: one .epub per language in db,
+			// unless --language narrows it down to just one.
+			exportedLanguages := epubLanguages(db, language)
+			for _, exportedLanguage := range exportedLanguages {
+				opts.Language = exportedLanguage
+				epub, err := BuildEPUB(db, opts)
+				if err != nil {
+					return err
+				}
+				path := outputPath
+				if len(exportedLanguages) > 1 {
+					path = epubOutputPathForLanguage(outputPath, exportedLanguage)
+				}
+				if err := os.WriteFile(path, epub, 0644); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&language, "language", "", "only export this language (default: every language)")
+	cmd.Flags().StringVar(&coverPath, "cover", "", "path to a cover image")
+	cmd.Flags().StringVar(&stylesheetPath, "stylesheet", "", "path to a CSS stylesheet to embed")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "portfolio.epub", "path to write the generated EPUB to")
+	return cmd
+}
+
+// epubOutputPathForLanguage inserts language before base's extension, e.g.
+// ("portfolio.epub", "fr") -> "portfolio.fr.epub", so exporting every language doesn't have
+// each one silently overwrite the last.
+func epubOutputPathForLanguage(base string, language string) string {
+	extension := ".epub"
+	stem := strings.TrimSuffix(base, extension)
+	if stem == base {
+		// base didn't have the expected extension: just append the language before it's used as-is.
+		return base + "." + language
+	}
+	return stem + "." + language + extension
+}
+
+// mimeTypeFromExtension is a small, self-contained extension→MIME map for --cover, since the
+// EPUB writer needs a MIME type but doesn't otherwise depend on the main media analyzer.
+func mimeTypeFromExtension(filePath string) string {
+	switch {
+	case hasAnySuffix(filePath, ".png"):
+		return "image/png"
+	case hasAnySuffix(filePath, ".jpg", ".jpeg"):
+		return "image/jpeg"
+	case hasAnySuffix(filePath, ".gif"):
+		return "image/gif"
+	case hasAnySuffix(filePath, ".webp"):
+		return "image/webp"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix {
+			return true
+		}
+	}
+	return false
+}
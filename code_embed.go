@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-enry/go-enry/v2"
+)
+
+// codeEmbedConfidenceThreshold is the minimum detection confidence (see
+// ResolveCodeEmbedLanguage) below which a CodeEmbed's language falls back to "text" rather
+// than risk a wrong highlighter.
+const codeEmbedConfidenceThreshold = 0.5
+
+// codeEmbedExtensions lists the file extensions (without the dot) that parseSingleLanguageDescription
+// treats as a source-code embed rather than a regular media embed, when no explicit
+// lang: directive is given.
+var codeEmbedExtensions = map[string]bool{
+	"go": true, "py": true, "rb": true, "js": true, "jsx": true, "ts": true, "tsx": true,
+	"rs": true, "c": true, "h": true, "hpp": true, "cpp": true, "cc": true, "java": true,
+	"kt": true, "swift": true, "php": true, "sh": true, "bash": true, "zsh": true,
+	"lua": true, "pl": true, "r": true, "scala": true, "hs": true, "ex": true, "exs": true,
+	"clj": true, "sql": true, "toml": true, "yaml": true, "yml": true,
+}
+
+// isCodeEmbedSource reports whether source's extension marks it as a source-code embed.
+func isCodeEmbedSource(source string) bool {
+	extension := strings.TrimPrefix(strings.ToLower(filepath.Ext(source)), ".")
+	return codeEmbedExtensions[extension]
+}
+
+// codeEmbedDirectivePattern matches the explicit code-embed syntax carried in a Markdown
+// image's title attribute, e.g. `![alt](snippet.txt "lang:go start:10 end:40")`.
+var codeEmbedDirectivePattern = regexp.MustCompile(`^lang:(\S+)(?:\s+start:(\d+))?(?:\s+end:(\d+))?$`)
+
+// parseCodeEmbedDirective parses title (an <img>'s title attribute) for the explicit
+// `lang:... start:... end:...` code-embed syntax. ok is false when title doesn't carry
+// this syntax, in which case the embed must instead be detected from its source extension.
+func parseCodeEmbedDirective(title string) (language string, startLine int, endLine int, ok bool) {
+	groups := codeEmbedDirectivePattern.FindStringSubmatch(strings.TrimSpace(title))
+	if groups == nil {
+		return "", 0, 0, false
+	}
+	startLine, _ = strconv.Atoi(groups[2])
+	endLine, _ = strconv.Atoi(groups[3])
+	return groups[1], startLine, endLine, true
+}
+
+// ResolveCodeEmbedLanguage fills in embed's Language, Snippet and Confidence once the
+// referenced source file's content is available: it's a separate step from parsing because
+// CodeEmbed, like MediaEmbedDeclaration, isn't supposed to touch the filesystem at parse time.
+// Detection combines go-enry's extension and content heuristics; when content is ambiguous
+// (no single language is inferable from the extension alone) and the resulting confidence
+// is below threshold, Language falls back to "text" rather than mislabeling the snippet.
+func ResolveCodeEmbedLanguage(embed CodeEmbed, content string, threshold float64) CodeEmbed {
+	embed.Snippet = extractLineRange(content, embed.StartLine, embed.EndLine)
+	if embed.Language != "" {
+		// Explicit lang: directive: trust the author, no detection needed.
+		embed.Confidence = 1
+		return embed
+	}
+	language, confidence := detectCodeLanguage(embed.Source, embed.Snippet)
+	if confidence < threshold {
+		language = "text"
+	}
+	embed.Language = language
+	embed.Confidence = confidence
+	return embed
+}
+
+// detectCodeLanguage guesses a source file's language from its filename and content.
+// An unambiguous extension (enry.GetLanguageByExtension's safe result) is trusted fully;
+// otherwise we fall back to enry's combined heuristics (content classifiers, interpreter
+// lines, ...), graded by enry's own classifier score via GetLanguageConfidence rather than a
+// single flat value, since those heuristics can be more or less sure of themselves depending
+// on how distinctive the content is — a flat confidence would make codeEmbedConfidenceThreshold
+// an all-or-nothing switch instead of an actual threshold.
+func detectCodeLanguage(filename string, content string) (language string, confidence float64) {
+	if language, safe := enry.GetLanguageByExtension(filename); safe {
+		return language, 1
+	}
+	guess := enry.GetLanguage(filename, []byte(content))
+	if guess == "" {
+		return "", 0
+	}
+	if graded, ok := enry.GetLanguageConfidence([]byte(content), guess); ok {
+		return guess, graded
+	}
+	return guess, 0.5
+}
+
+// extractLineRange returns the lines [start, end] (1-indexed, inclusive) of content, or the
+// whole content when start/end are zero (meaning "no range was specified").
+func extractLineRange(content string, start int, end int) string {
+	if start == 0 && end == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end || start > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start-1:end], "\n")
+}
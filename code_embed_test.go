@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestParseCodeEmbedDirective(t *testing.T) {
+	cases := []struct {
+		name         string
+		title        string
+		wantLanguage string
+		wantStart    int
+		wantEnd      int
+		wantExplicit bool
+	}{
+		{"full directive", "lang:go start:10 end:40", "go", 10, 40, true},
+		{"language only", "lang:python", "python", 0, 0, true},
+		{"start without end", "lang:rust start:5", "rust", 5, 0, true},
+		{"not a directive", "just a regular title", "", 0, 0, false},
+		{"empty title", "", "", 0, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			language, start, end, ok := parseCodeEmbedDirective(c.title)
+			if ok != c.wantExplicit || language != c.wantLanguage || start != c.wantStart || end != c.wantEnd {
+				t.Errorf("parseCodeEmbedDirective(%q) = (%q, %d, %d, %v), want (%q, %d, %d, %v)",
+					c.title, language, start, end, ok, c.wantLanguage, c.wantStart, c.wantEnd, c.wantExplicit)
+			}
+		})
+	}
+}
+
+func TestIsCodeEmbedSource(t *testing.T) {
+	cases := []struct {
+		source string
+		want   bool
+	}{
+		{"main.go", true},
+		{"script.py", true},
+		{"style.yaml", true},
+		{"photo.jpg", false},
+		{"clip.mp4", false},
+		{"noextension", false},
+	}
+	for _, c := range cases {
+		if got := isCodeEmbedSource(c.source); got != c.want {
+			t.Errorf("isCodeEmbedSource(%q) = %v, want %v", c.source, got, c.want)
+		}
+	}
+}
+
+func TestExtractLineRange(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive"
+	cases := []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{"no range returns everything", 0, 0, content},
+		{"middle range", 2, 4, "two\nthree\nfour"},
+		{"end beyond content clamps", 4, 100, "four\nfive"},
+		{"start beyond content is empty", 100, 0, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := extractLineRange(content, c.start, c.end); got != c.want {
+				t.Errorf("extractLineRange(%d, %d) = %q, want %q", c.start, c.end, got, c.want)
+			}
+		})
+	}
+}
+
+func TestDetectCodeLanguageUnambiguousExtension(t *testing.T) {
+	language, confidence := detectCodeLanguage("main.go", "package main\n")
+	if language != "Go" {
+		t.Errorf("detectCodeLanguage(main.go) language = %q, want %q", language, "Go")
+	}
+	if confidence != 1 {
+		t.Errorf("detectCodeLanguage(main.go) confidence = %v, want 1 (unambiguous extension)", confidence)
+	}
+}
+
+func TestResolveCodeEmbedLanguageTrustsExplicitDirective(t *testing.T) {
+	embed := CodeEmbed{Source: "mystery.txt", Language: "go", StartLine: 1, EndLine: 2}
+	resolved := ResolveCodeEmbedLanguage(embed, "line one\nline two\nline three", codeEmbedConfidenceThreshold)
+	if resolved.Language != "go" || resolved.Confidence != 1 {
+		t.Errorf("got Language=%q Confidence=%v, want Language=%q Confidence=1 (explicit lang: directive is trusted as-is)",
+			resolved.Language, resolved.Confidence, "go")
+	}
+	if resolved.Snippet != "line one\nline two" {
+		t.Errorf("Snippet = %q, want the StartLine..EndLine slice %q", resolved.Snippet, "line one\nline two")
+	}
+}
+
+func TestResolveCodeEmbedLanguageFallsBackBelowThreshold(t *testing.T) {
+	embed := CodeEmbed{Source: "mystery.txt"}
+	// A threshold above 1 forces the fallback regardless of what detectCodeLanguage guesses,
+	// since confidence never exceeds 1.
+	resolved := ResolveCodeEmbedLanguage(embed, "some plain, ambiguous content", 2)
+	if resolved.Language != "text" {
+		t.Errorf("Language = %q, want %q for a below-threshold detection", resolved.Language, "text")
+	}
+}
@@ -10,8 +10,6 @@ import (
 	"github.com/anaskhan96/soup"
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/parser"
-
-	"github.com/metal3d/go-slugify"
 )
 
 const (
@@ -50,12 +48,17 @@ func ParseYAMLHeader(descriptionRaw string) (map[string]interface{}, string) {
 	return parsedYAMLPart, markdownPart
 }
 
-// ParseDescription parses the markdown string from a description.md file and returns a ParsedDescription
-func ParseDescription(ctx RunContext, markdownRaw string) ParsedDescription {
-	ctx.Status("Parsing description.md")
-	metadata, markdownRaw := ParseYAMLHeader(markdownRaw)
-	// notLocalizedRaw: raw markdown before the first language marker
-	notLocalizedRaw, localizedRawBlocks := splitOnLanguageMarkers(markdownRaw)
+// ParseDescription parses the raw contents of a description file (path is used to pick which
+// DescriptionReader handles it) and returns a ParsedDescription
+func ParseDescription(ctx RunContext, path string, descriptionRaw string) ParsedDescription {
+	ctx.Status("Parsing description file")
+	metadata, descriptionRaw := ParseYAMLHeader(descriptionRaw)
+	format, _ := metadata["format"].(string)
+	reader := detectDescriptionReader(path, descriptionRaw, format)
+	// notLocalizedRaw: raw content before the first language marker. Language markers are
+	// plain-text and checked for before the reader runs, so they work the same regardless
+	// of the description's format.
+	notLocalizedRaw, localizedRawBlocks := splitOnLanguageMarkers(descriptionRaw)
 	localized := len(localizedRawBlocks) > 0
 	var allLanguages []string
 	if localized {
@@ -66,6 +69,8 @@ func ParseDescription(ctx RunContext, markdownRaw string) ParsedDescription {
 	}
 	paragraphs := make(map[string][]Paragraph, 0)
 	mediaEmbedDeclarations := make(map[string][]MediaEmbedDeclaration, 0)
+	codeEmbeds := make(map[string][]CodeEmbed, 0)
+	iframeEmbeds := make(map[string][]IframeEmbed, 0)
 	links := make(map[string][]Link, 0)
 	title := make(map[string]string, 0)
 	footnotes := make(map[string][]Footnote, 0)
@@ -76,7 +81,7 @@ func ParseDescription(ctx RunContext, markdownRaw string) ParsedDescription {
 		if localized {
 			raw += localizedRawBlocks[language]
 		}
-		title[language], paragraphs[language], mediaEmbedDeclarations[language], links[language], footnotes[language], abbreviations[language] = parseSingleLanguageDescription(raw)
+		title[language], paragraphs[language], mediaEmbedDeclarations[language], codeEmbeds[language], iframeEmbeds[language], links[language], footnotes[language], abbreviations[language] = parseSingleLanguageDescription(reader, raw)
 	}
 	return ParsedDescription{
 		Metadata:               metadata,
@@ -84,12 +89,15 @@ func ParseDescription(ctx RunContext, markdownRaw string) ParsedDescription {
 		Links:                  links,
 		Title:                  title,
 		MediaEmbedDeclarations: mediaEmbedDeclarations,
+		CodeEmbeds:             codeEmbeds,
+		IframeEmbeds:           iframeEmbeds,
 		Footnotes:              footnotes,
 	}
 }
 
 // Abbreviation represents an abbreviation declaration in a description.md file
 type Abbreviation struct {
+	ID         string
 	Name       string
 	Definition string
 }
@@ -130,10 +138,23 @@ type Work struct {
 type MediaEmbedDeclaration struct {
 	Alt        string
 	Title      string
+	Caption    string // From a `.caption` line below the embed, or the “ ”-quoted shorthand in Alt.
 	Source     string
+	StartTime  int // Video playback start time in seconds, from a `#t=start,end` fragment. -1 when unset.
+	EndTime    int // Video playback end time in seconds, from a `#t=start,end` fragment. -1 when unset.
 	Attributes MediaAttributes
 }
 
+// IframeEmbed represents an embedded external frame, declared with the present-style
+// `.iframe url WIDTHxHEIGHT` directive (see handlePresentStyleDirectives).
+type IframeEmbed struct {
+	Caption string
+	Source  string
+	Width   int
+	Height  int
+	Sandbox string
+}
+
 // MediaAttributes stores which HTML attributes should be added to the media
 type MediaAttributes struct {
 	Looped      bool // Controlled with attribute character ~ (adds)
@@ -143,12 +164,28 @@ type MediaAttributes struct {
 	Controls    bool // Controlled with attribute character = (removes)
 }
 
+// CodeEmbed represents a request to embed a source file (or a line range of it) as a
+// syntax-highlighted code block. Only stores the info extracted from the syntax, no
+// filesystem interactions: Language/Snippet/Confidence are filled in later, once the
+// referenced file's content is available, by ResolveCodeEmbedLanguage.
+type CodeEmbed struct {
+	Alt        string
+	Source     string
+	Language   string
+	StartLine  int
+	EndLine    int
+	Snippet    string
+	Confidence float64
+}
+
 // ParsedDescription represents a work, but without analyzed media. All it contains is information from the description.md file
 type ParsedDescription struct {
 	Metadata               map[string]interface{}
 	Title                  map[string]string
 	Paragraphs             map[string][]Paragraph
 	MediaEmbedDeclarations map[string][]MediaEmbedDeclaration
+	CodeEmbeds             map[string][]CodeEmbed
+	IframeEmbeds           map[string][]IframeEmbed
 	Links                  map[string][]Link
 	Footnotes              map[string][]Footnote
 }
@@ -176,32 +213,98 @@ func splitOnLanguageMarkers(markdownRaw string) (string, map[string]string) {
 	return before, markdownRawPerLanguage
 }
 
-// parseSingleLanguageDescription takes in raw markdown without language markers (called on splitOnLanguageMarker's output)
-// and returns parsed arrays of structs that make up each language's part in ParsedDescription's maps
-func parseSingleLanguageDescription(markdownRaw string) (string, []Paragraph, []MediaEmbedDeclaration, []Link, []Footnote, []Abbreviation) {
-	markdownRaw = handleAltMediaEmbedSyntax(markdownRaw)
-	htmlRaw := markdownToHTML(markdownRaw)
+// parseSingleLanguageDescription takes in raw description content without language markers
+// (called on splitOnLanguageMarker's output) and returns parsed arrays of structs that make
+// up each language's part in ParsedDescription's maps. reader converts that raw content to
+// HTML; the rest of this function is format-agnostic.
+func parseSingleLanguageDescription(reader DescriptionReader, markdownRaw string) (string, []Paragraph, []MediaEmbedDeclaration, []CodeEmbed, []IframeEmbed, []Link, []Footnote, []Abbreviation) {
+	if reader.Name() == (MarkdownDescriptionReader{}).Name() {
+		markdownRaw = handleAltMediaEmbedSyntax(markdownRaw)
+		markdownRaw = handlePresentStyleDirectives(markdownRaw)
+	}
+	htmlRaw, err := reader.ToHTML(markdownRaw)
+	if err != nil {
+		// Malformed markup shouldn't crash the whole build: fall back to an empty document
+		// for this language, same as an empty description.md would produce.
+		htmlRaw = ""
+	}
 	htmlTree := soup.HTMLParse(htmlRaw)
 	paragraphs := make([]Paragraph, 0)
 	mediae := make([]MediaEmbedDeclaration, 0)
+	codeEmbeds := make([]CodeEmbed, 0)
+	iframeEmbeds := make([]IframeEmbed, 0)
 	links := make([]Link, 0)
 	footnotes := make([]Footnote, 0)
 	abbreviations := make([]Abbreviation, 0)
-	for _, paragraph := range htmlTree.FindAll("p") {
+	seenSlugs := make(map[string]int)
+	// lastEmbedKind/lastEmbedIndex track the most recently appended visual embed, so a
+	// `.caption` line (which appears in its own paragraph right below one) can be attached
+	// to it instead of becoming its own Paragraph.
+	lastEmbedKind := ""
+	lastEmbedIndex := -1
+	// Walking collectParagraphsAndIframes (rather than htmlTree.FindAll("p")) matters here:
+	// unlike <img>, which gomarkdown always wraps in a <p>, a bare <iframe>...</iframe> block
+	// is emitted as its own top-level element, a sibling of the surrounding <p>s rather than
+	// nested in one. Iterating in document order also lets a `.caption` paragraph attach to
+	// whichever embed (media or iframe) immediately precedes it.
+	for _, element := range collectParagraphsAndIframes(htmlTree.Find("body")) {
+		if element.NodeValue == "iframe" {
+			width, height := parseIframeDimensions(element.Attrs()["width"], element.Attrs()["height"])
+			iframeEmbeds = append(iframeEmbeds, IframeEmbed{
+				Source:  element.Attrs()["src"],
+				Width:   width,
+				Height:  height,
+				Sandbox: element.Attrs()["data-sandbox"],
+			})
+			lastEmbedKind, lastEmbedIndex = "iframe", len(iframeEmbeds)-1
+			continue
+		}
+		if element.NodeValue != "p" || len(element.Children()) == 0 {
+			continue
+		}
+		paragraph := element
 		childrenCount := len(paragraph.Children())
 		firstChild := paragraph.Children()[0]
 		if childrenCount == 1 && firstChild.NodeValue == "img" {
-			alt, title := extractTitleFromMediaAlt(firstChild.Attrs()["alt"])
-			alt, attributes := extractAttributesFromAlt(alt)
-			mediae = append(mediae, MediaEmbedDeclaration{
-				Alt:        alt,
-				Title:      title,
-				Source:     firstChild.Attrs()["src"],
-				Attributes: attributes,
-			})
+			src := firstChild.Attrs()["src"]
+			altRaw := firstChild.Attrs()["alt"]
+			if language, startLine, endLine, explicit := parseCodeEmbedDirective(firstChild.Attrs()["title"]); explicit || isCodeEmbedSource(src) {
+				codeEmbeds = append(codeEmbeds, CodeEmbed{
+					Alt:       altRaw,
+					Source:    src,
+					Language:  language,
+					StartLine: startLine,
+					EndLine:   endLine,
+				})
+			} else {
+				alt, title := extractTitleFromMediaAlt(altRaw)
+				alt, attributes := extractAttributesFromAlt(alt)
+				startTime, endTime := -1, -1
+				if firstChild.Attrs()["data-video"] != "" {
+					startTime, endTime = parseVideoTimeRange(firstChild.Attrs()["data-t-start"], firstChild.Attrs()["data-t-end"])
+				}
+				mediae = append(mediae, MediaEmbedDeclaration{
+					Alt:        alt,
+					Title:      title,
+					Caption:    title, // The “ ”-quoted shorthand in Alt, until/unless a .caption line overrides it below.
+					Source:     src,
+					StartTime:  startTime,
+					EndTime:    endTime,
+					Attributes: attributes,
+				})
+				lastEmbedKind, lastEmbedIndex = "media", len(mediae)-1
+			}
+		} else if paragraph.Attrs()["data-caption"] == "1" {
+			caption := innerHTML(paragraph)
+			switch lastEmbedKind {
+			case "media":
+				mediae[lastEmbedIndex].Caption = caption
+			case "iframe":
+				iframeEmbeds[lastEmbedIndex].Caption = caption
+			}
 		} else if childrenCount == 1 && firstChild.NodeValue == "a" {
 			links = append(links, Link{
-				ID:    slugify.Marshal(firstChild.FullText()),
+				ID:    deduplicateSlug(UnicodeSlug(firstChild.FullText()), seenSlugs),
 				Name:  innerHTML(firstChild),
 				Title: firstChild.Attrs()["title"],
 				URL:   firstChild.Attrs()["href"],
@@ -209,14 +312,19 @@ func parseSingleLanguageDescription(markdownRaw string) (string, []Paragraph, []
 		} else if RegexpMatches(patternAbbreviationDefinition, innerHTML(paragraph)) {
 			groups := RegexpGroups(patternAbbreviationDefinition, innerHTML(paragraph))
 			abbreviations = append(abbreviations, Abbreviation{
+				ID:         deduplicateSlug(UnicodeSlug(groups[1]), seenSlugs),
 				Name:       groups[1],
 				Definition: groups[2],
 			})
 		} else if RegexpMatches(patternLanguageMarker, innerHTML(paragraph)) {
 			continue
 		} else {
+			id := paragraph.Attrs()["id"]
+			if id == "" {
+				id = UnicodeSlug(innerHTML(paragraph))
+			}
 			paragraphs = append(paragraphs, Paragraph{
-				ID:      paragraph.Attrs()["id"],
+				ID:      deduplicateSlug(id, seenSlugs),
 				Content: innerHTML(paragraph),
 			})
 		}
@@ -226,7 +334,7 @@ func parseSingleLanguageDescription(markdownRaw string) (string, []Paragraph, []
 		if div.Attrs()["class"] == "footnotes" {
 			for _, li := range div.FindAll("li") {
 				footnotes = append(footnotes, Footnote{
-					Name:    strings.TrimPrefix(li.Attrs()["id"], "fn:"),
+					Name:    deduplicateSlug(UnicodeSlug(strings.TrimPrefix(li.Attrs()["id"], "fn:")), seenSlugs),
 					Content: innerHTML(li),
 				})
 			}
@@ -236,7 +344,24 @@ func parseSingleLanguageDescription(markdownRaw string) (string, []Paragraph, []
 	for _, paragraph := range paragraphs {
 		processedParagraphs = append(processedParagraphs, processParagraph(paragraph, abbreviations))
 	}
-	return title, processedParagraphs, mediae, links, footnotes, abbreviations
+	return title, processedParagraphs, mediae, codeEmbeds, iframeEmbeds, links, footnotes, abbreviations
+}
+
+// collectParagraphsAndIframes walks root's descendants in document order and returns every
+// <p> and <iframe>, recursing into other block containers (blockquote, li, table cells, ...)
+// so a paragraph nested inside one of those isn't missed. It doesn't recurse into <p> or
+// <iframe> themselves, since their children are inline content the rest of the pipeline
+// handles directly (firstChild checks, innerHTML, ...).
+func collectParagraphsAndIframes(root soup.Root) []soup.Root {
+	var elements []soup.Root
+	for _, child := range root.Children() {
+		if child.NodeValue == "p" || child.NodeValue == "iframe" {
+			elements = append(elements, child)
+			continue
+		}
+		elements = append(elements, collectParagraphsAndIframes(child)...)
+	}
+	return elements
 }
 
 // handleAltMediaEmbedSyntax handles the >[...](...) syntax by replacing it in htmlRaw with ![...](...)
@@ -330,5 +455,5 @@ func processParagraph(paragraph Paragraph, currentLanguageAbbreviations []Abbrev
 		processed = replacePattern.ReplaceAllString(paragraph.Content, "<abbr title=\""+abbreviation.Definition+"\">"+abbreviation.Name+"</abbr>")
 	}
 
-	return Paragraph{Content: processed}
+	return Paragraph{ID: paragraph.ID, Content: processed}
 }
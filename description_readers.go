@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DescriptionReader converts a description file's raw markup into HTML. Implementing this
+// interface lets description.md authors write in formats other than Markdown, while the
+// rest of the pipeline (language-marker splitting, and the soup-based paragraph/link/media
+// extraction in parseSingleLanguageDescription) stays format-agnostic and operates purely
+// on the HTML a reader produces.
+type DescriptionReader interface {
+	// Name identifies the reader; matched against the `format:` YAML header key.
+	Name() string
+	// Detect reports whether this reader should handle a file, based on its path and raw content.
+	Detect(path string, raw string) bool
+	// ToHTML converts raw markup into HTML.
+	ToHTML(raw string) (string, error)
+}
+
+// descriptionReaders lists the available readers, in the order Detect is tried when no
+// explicit format: is set.
+var descriptionReaders = []DescriptionReader{
+	MarkdownDescriptionReader{},
+	RestructuredTextDescriptionReader{},
+	OrgModeDescriptionReader{},
+	AsciiDocDescriptionReader{},
+}
+
+// detectDescriptionReader picks the DescriptionReader for a description file: an explicit
+// format (from the YAML header) takes precedence, then each reader is asked whether it
+// recognizes path/raw, falling back to Markdown, the historical default.
+func detectDescriptionReader(path string, raw string, format string) DescriptionReader {
+	if format != "" {
+		for _, reader := range descriptionReaders {
+			if strings.EqualFold(reader.Name(), format) {
+				return reader
+			}
+		}
+	}
+	for _, reader := range descriptionReaders {
+		if reader.Detect(path, raw) {
+			return reader
+		}
+	}
+	return MarkdownDescriptionReader{}
+}
+
+// MarkdownDescriptionReader is the historical, default description format.
+type MarkdownDescriptionReader struct{}
+
+func (MarkdownDescriptionReader) Name() string { return "markdown" }
+
+func (MarkdownDescriptionReader) Detect(path string, _ string) bool {
+	return strings.HasSuffix(path, ".md")
+}
+
+func (MarkdownDescriptionReader) ToHTML(raw string) (string, error) {
+	return markdownToHTML(raw), nil
+}
+
+// blockPattern splits raw text into blank-line-separated blocks, the common notion of a
+// "paragraph" shared by RST, Org and AsciiDoc.
+var blockPattern = regexp.MustCompile(`\n\s*\n`)
+
+// alreadyBlockTagPattern matches a block that a reader has already turned into a block-level
+// HTML tag (e.g. <h1>, <li>, <p>), which wrapPlainParagraphs should leave untouched.
+var alreadyBlockTagPattern = regexp.MustCompile(`(?s)^\s*<(h1|h2|h3|p|li|ul|ol|img)[\s>]`)
+
+// wrapPlainParagraphs wraps every block of html that isn't already a block-level HTML tag
+// in a <p>, joining its lines with a space. It's shared by the non-Markdown readers, whose
+// directive handling only rewrites the blocks it recognizes and leaves everything else as
+// plain prose.
+func wrapPlainParagraphs(html string) string {
+	blocks := blockPattern.Split(html, -1)
+	wrapped := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		if alreadyBlockTagPattern.MatchString(block) {
+			wrapped = append(wrapped, block)
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+		wrapped = append(wrapped, "<p>"+strings.Join(lines, " ")+"</p>")
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// RestructuredTextDescriptionReader supports reStructuredText description files
+// (description.rst). It covers the subset portfoliodb needs: a title underline, bullet
+// lists and the `.. image::` directive, which is surfaced as a plain <img> so it flows
+// through the same MediaEmbedDeclaration extraction as a Markdown image.
+type RestructuredTextDescriptionReader struct{}
+
+func (RestructuredTextDescriptionReader) Name() string { return "rst" }
+
+func (RestructuredTextDescriptionReader) Detect(path string, _ string) bool {
+	return strings.HasSuffix(path, ".rst")
+}
+
+var (
+	rstImageDirectivePattern = regexp.MustCompile(`(?m)^\.\.\s+image::\s*(\S+)\s*\n((?:[ \t]+:\w+:.*\n?)*)`)
+	rstImageOptionPattern    = regexp.MustCompile(`(?m)^[ \t]+:(\w+):\s*(.*)$`)
+	rstUnderlinePattern      = regexp.MustCompile(`(?m)^(\S.*)\n[=\-~^"]{3,}[ \t]*$`)
+	rstBulletPattern         = regexp.MustCompile(`(?m)^[*-][ \t]+(.+)$`)
+)
+
+func (RestructuredTextDescriptionReader) ToHTML(raw string) (string, error) {
+	html := rstImageDirectivePattern.ReplaceAllStringFunc(raw, func(match string) string {
+		groups := rstImageDirectivePattern.FindStringSubmatch(match)
+		src, options := groups[1], groups[2]
+		alt, caption := "", ""
+		for _, option := range rstImageOptionPattern.FindAllStringSubmatch(options, -1) {
+			switch option[1] {
+			case "alt":
+				alt = option[2]
+			case "caption":
+				caption = option[2]
+			}
+		}
+		if caption != "" {
+			alt = caption
+		}
+		return fmt.Sprintf("<p><img src=%q alt=%q></p>\n", src, alt)
+	})
+	html = rstUnderlinePattern.ReplaceAllString(html, "<h1>$1</h1>")
+	html = rstBulletPattern.ReplaceAllString(html, "<li>$1</li>")
+	return wrapPlainParagraphs(html), nil
+}
+
+// OrgModeDescriptionReader supports Org-mode description files (description.org): a
+// top-level heading, and `[[file:path]]` links become a MediaEmbedDeclaration. A `#+CAPTION:`
+// line preceding the link becomes the embed's Alt; its Caption and Title stay empty.
+type OrgModeDescriptionReader struct{}
+
+func (OrgModeDescriptionReader) Name() string { return "org" }
+
+func (OrgModeDescriptionReader) Detect(path string, _ string) bool {
+	return strings.HasSuffix(path, ".org")
+}
+
+var (
+	orgHeadingPattern   = regexp.MustCompile(`(?m)^\*\s+(.+)$`)
+	orgCaptionedImage   = regexp.MustCompile(`(?m)^#\+CAPTION:\s*(.+)\n\[\[file:([^\]]+)\]\]\s*$`)
+	orgBareImagePattern = regexp.MustCompile(`(?m)^\[\[file:([^\]]+)\]\]\s*$`)
+	orgBulletPattern    = regexp.MustCompile(`(?m)^[-+]\s+(.+)$`)
+)
+
+func (OrgModeDescriptionReader) ToHTML(raw string) (string, error) {
+	html := orgCaptionedImage.ReplaceAllString(raw, `<p><img src="$2" alt="$1"></p>`)
+	html = orgBareImagePattern.ReplaceAllString(html, `<p><img src="$1" alt=""></p>`)
+	html = orgHeadingPattern.ReplaceAllString(html, "<h1>$1</h1>")
+	html = orgBulletPattern.ReplaceAllString(html, "<li>$1</li>")
+	return wrapPlainParagraphs(html), nil
+}
+
+// AsciiDocDescriptionReader supports AsciiDoc description files (description.adoc): a
+// document title and block images (`image::path[alt]`), surfaced as a plain <img>.
+type AsciiDocDescriptionReader struct{}
+
+func (AsciiDocDescriptionReader) Name() string { return "asciidoc" }
+
+func (AsciiDocDescriptionReader) Detect(path string, _ string) bool {
+	return strings.HasSuffix(path, ".adoc")
+}
+
+var (
+	adocTitlePattern  = regexp.MustCompile(`(?m)^=\s+(.+)$`)
+	adocImagePattern  = regexp.MustCompile(`(?m)^image::([^\[]+)\[([^\]]*)\]\s*$`)
+	adocBulletPattern = regexp.MustCompile(`(?m)^\*\s+(.+)$`)
+)
+
+func (AsciiDocDescriptionReader) ToHTML(raw string) (string, error) {
+	html := adocImagePattern.ReplaceAllString(raw, `<p><img src="$1" alt="$2"></p>`)
+	html = adocTitlePattern.ReplaceAllString(html, "<h1>$1</h1>")
+	html = adocBulletPattern.ReplaceAllString(html, "<li>$1</li>")
+	return wrapPlainParagraphs(html), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import "testing"
+
+func TestParseDescriptionRestructuredText(t *testing.T) {
+	raw := "Title\n" +
+		"=====\n" +
+		"\n" +
+		"Hello world.\n" +
+		"\n" +
+		".. image:: photo.jpg\n" +
+		"   :alt: A photo\n"
+
+	parsed := ParseDescription(RunContext{}, "description.rst", raw)
+
+	if got := parsed.Title["default"]; got != "Title" {
+		t.Errorf("Title = %q, want %q", got, "Title")
+	}
+	if paragraphs := parsed.Paragraphs["default"]; len(paragraphs) != 1 || paragraphs[0].Content != "Hello world." {
+		t.Errorf("Paragraphs = %+v, want a single paragraph with content %q", paragraphs, "Hello world.")
+	}
+	if mediae := parsed.MediaEmbedDeclarations["default"]; len(mediae) != 1 || mediae[0].Source != "photo.jpg" || mediae[0].Alt != "A photo" {
+		t.Errorf("MediaEmbedDeclarations = %+v, want one embed of photo.jpg with alt %q", mediae, "A photo")
+	}
+}
+
+func TestParseDescriptionOrgMode(t *testing.T) {
+	raw := "* Title\n" +
+		"\n" +
+		"Hello world.\n" +
+		"\n" +
+		"#+CAPTION: A photo\n" +
+		"[[file:photo.jpg]]\n"
+
+	parsed := ParseDescription(RunContext{}, "description.org", raw)
+
+	if got := parsed.Title["default"]; got != "Title" {
+		t.Errorf("Title = %q, want %q", got, "Title")
+	}
+	if paragraphs := parsed.Paragraphs["default"]; len(paragraphs) != 1 || paragraphs[0].Content != "Hello world." {
+		t.Errorf("Paragraphs = %+v, want a single paragraph with content %q", paragraphs, "Hello world.")
+	}
+	if mediae := parsed.MediaEmbedDeclarations["default"]; len(mediae) != 1 || mediae[0].Source != "photo.jpg" || mediae[0].Alt != "A photo" {
+		t.Errorf("MediaEmbedDeclarations = %+v, want one embed of photo.jpg with alt %q", mediae, "A photo")
+	}
+}
+
+func TestParseDescriptionAsciiDoc(t *testing.T) {
+	raw := "= Title\n" +
+		"\n" +
+		"Hello world.\n" +
+		"\n" +
+		"image::photo.jpg[A photo]\n"
+
+	parsed := ParseDescription(RunContext{}, "description.adoc", raw)
+
+	if got := parsed.Title["default"]; got != "Title" {
+		t.Errorf("Title = %q, want %q", got, "Title")
+	}
+	if paragraphs := parsed.Paragraphs["default"]; len(paragraphs) != 1 || paragraphs[0].Content != "Hello world." {
+		t.Errorf("Paragraphs = %+v, want a single paragraph with content %q", paragraphs, "Hello world.")
+	}
+	if mediae := parsed.MediaEmbedDeclarations["default"]; len(mediae) != 1 || mediae[0].Source != "photo.jpg" || mediae[0].Alt != "A photo" {
+		t.Errorf("MediaEmbedDeclarations = %+v, want one embed of photo.jpg with alt %q", mediae, "A photo")
+	}
+}
+
+func TestParagraphIDSurvivesProcessParagraph(t *testing.T) {
+	raw := "Hello world.\n"
+	parsed := ParseDescription(RunContext{}, "description.md", raw)
+
+	paragraphs := parsed.Paragraphs["default"]
+	if len(paragraphs) != 1 {
+		t.Fatalf("Paragraphs = %+v, want exactly one paragraph", paragraphs)
+	}
+	if paragraphs[0].ID == "" {
+		t.Errorf("Paragraphs[0].ID is empty, want the UnicodeSlug fallback ID to survive processParagraph")
+	}
+	if want := UnicodeSlug(paragraphs[0].Content); paragraphs[0].ID != want {
+		t.Errorf("Paragraphs[0].ID = %q, want %q (UnicodeSlug of the content)", paragraphs[0].ID, want)
+	}
+}
+
+func TestDetectDescriptionReader(t *testing.T) {
+	cases := []struct {
+		name   string
+		path   string
+		format string
+		want   string
+	}{
+		{"markdown by extension", "description.md", "", "markdown"},
+		{"rst by extension", "description.rst", "", "rst"},
+		{"org by extension", "description.org", "", "org"},
+		{"asciidoc by extension", "description.adoc", "", "asciidoc"},
+		{"explicit format wins over extension", "description.md", "org", "org"},
+		{"unrecognized extension falls back to markdown", "description.txt", "", "markdown"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := detectDescriptionReader(c.path, "", c.format).Name(); got != c.want {
+				t.Errorf("detectDescriptionReader(%q, format=%q) = %q, want %q", c.path, c.format, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"gopkg.in/yaml.v2"
+)
+
+// templatedDescriptionSuffixes lists the filename endings that mark a description file as a
+// Go template to be executed before ParseDescription ever sees it, e.g. description.md.tmpl.
+var templatedDescriptionSuffixes = []string{".md.tmpl", ".tmpl.md"}
+
+// IsTemplatedDescription reports whether path names a templated description file.
+func IsTemplatedDescription(path string) bool {
+	for _, suffix := range templatedDescriptionSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDescription reads a work's description file at path, rendering it through
+// RenderDescriptionTemplate first when it's a templated description (description.md.tmpl or
+// description.tmpl.md). The result is plain markdown (or RST/Org/AsciiDoc), fed into
+// ParseDescription exactly like a static description file would be.
+func LoadDescription(ctx RunContext, path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	if !IsTemplatedDescription(path) {
+		return string(raw), nil
+	}
+	ctx.Status("Executing description template " + path)
+	return RenderDescriptionTemplate(path, string(raw))
+}
+
+// descriptionTemplateContext is exposed to description templates as the template's dot (`.`).
+type descriptionTemplateContext struct {
+	Directory string
+	Files     []templateFileInfo
+	Git       templateGitInfo
+	Data      map[string]interface{} // Merged contents of any sibling *.yaml/*.yml data files.
+}
+
+// templateFileInfo describes one file in the work's directory, exposed through `.Files`.
+type templateFileInfo struct {
+	Name string
+	Size int64
+}
+
+// templateGitInfo carries the description file's last-commit metadata.
+type templateGitInfo struct {
+	LastModified time.Time
+	Author       string
+}
+
+// RenderDescriptionTemplate executes raw (the contents of a description.md.tmpl) as a
+// text/template, with a context exposing the work directory's contents, git metadata, and
+// any sibling YAML data files, plus the listMedia/exif/embed/include helper funcs.
+func RenderDescriptionTemplate(path string, raw string) (string, error) {
+	directory := filepath.Dir(path)
+
+	ctx, err := buildDescriptionTemplateContext(directory, path)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"listMedia": func(pattern string) ([]string, error) {
+			globPath, err := resolveWithinDirectory(directory, pattern)
+			if err != nil {
+				return nil, err
+			}
+			matches, err := filepath.Glob(globPath)
+			if err != nil {
+				return nil, err
+			}
+			for i, match := range matches {
+				matches[i], _ = filepath.Rel(directory, match)
+			}
+			return matches, nil
+		},
+		"exif": func(mediaPath string, tag string) (string, error) {
+			resolved, err := resolveWithinDirectory(directory, mediaPath)
+			if err != nil {
+				return "", err
+			}
+			return readEXIFTag(resolved, tag)
+		},
+		"embed": func(mediaPath string) string {
+			return fmt.Sprintf("![](%s)", mediaPath)
+		},
+		"include": func(includePath string) (string, error) {
+			resolved, err := resolveWithinDirectory(directory, includePath)
+			if err != nil {
+				return "", fmt.Errorf("include %q: %w", includePath, err)
+			}
+			content, err := os.ReadFile(resolved)
+			if err != nil {
+				return "", fmt.Errorf("include %q: %w", includePath, err)
+			}
+			return string(content), nil
+		},
+	}).Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("parsing description template %s: %w", path, err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, ctx); err != nil {
+		return "", fmt.Errorf("executing description template %s: %w", path, err)
+	}
+	return rendered.String(), nil
+}
+
+// resolveWithinDirectory joins directory with userPath and rejects the result if it escapes
+// directory (e.g. userPath is "../../etc/passwd"). listMedia/exif/include all take a path
+// straight from the description template's author, and a template is otherwise free to run
+// arbitrary control flow, so without this check any of them could read files far outside the
+// work directory.
+func resolveWithinDirectory(directory string, userPath string) (string, error) {
+	resolved := filepath.Join(directory, userPath)
+	relative, err := filepath.Rel(directory, resolved)
+	if err != nil {
+		return "", fmt.Errorf("resolving %q: %w", userPath, err)
+	}
+	if relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q escapes the work directory", userPath)
+	}
+	return resolved, nil
+}
+
+// buildDescriptionTemplateContext gathers everything RenderDescriptionTemplate exposes as `.`:
+// the directory listing, git metadata for the description file, and any sibling YAML data.
+func buildDescriptionTemplateContext(directory string, descriptionPath string) (descriptionTemplateContext, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return descriptionTemplateContext{}, fmt.Errorf("listing %s: %w", directory, err)
+	}
+	files := make([]templateFileInfo, 0, len(entries))
+	data := make(map[string]interface{})
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, templateFileInfo{Name: entry.Name(), Size: info.Size()})
+		if extension := filepath.Ext(entry.Name()); extension == ".yaml" || extension == ".yml" {
+			mergeYAMLDataFile(filepath.Join(directory, entry.Name()), data)
+		}
+	}
+
+	return descriptionTemplateContext{
+		Directory: directory,
+		Files:     files,
+		Git:       readGitInfo(descriptionPath),
+		Data:      data,
+	}, nil
+}
+
+// mergeYAMLDataFile reads path as YAML and merges its top-level keys into data, under the
+// file's basename (without extension) so multiple sibling data files don't collide.
+func mergeYAMLDataFile(path string, data map[string]interface{}) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var parsed interface{}
+	if yaml.Unmarshal(raw, &parsed) != nil {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	data[name] = parsed
+}
+
+// readGitInfo runs `git log` on path to get its last commit's date and author. Returns the
+// zero value when the file isn't tracked (or git isn't available), rather than failing the
+// whole template render over optional metadata.
+func readGitInfo(path string) templateGitInfo {
+	output, err := exec.Command("git", "-C", filepath.Dir(path), "log", "-1", "--format=%at%n%an", "--", filepath.Base(path)).Output()
+	if err != nil {
+		return templateGitInfo{}
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)
+	if len(lines) != 2 {
+		return templateGitInfo{}
+	}
+	unixTime, err := strconv.ParseInt(lines[0], 10, 64)
+	if err != nil {
+		return templateGitInfo{}
+	}
+	return templateGitInfo{LastModified: time.Unix(unixTime, 0), Author: lines[1]}
+}
+
+// readEXIFTag reads a single EXIF tag (e.g. "DateTimeOriginal") from an image file.
+func readEXIFTag(path string, tag string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	metadata, err := exif.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("decoding EXIF data from %s: %w", path, err)
+	}
+	value, err := metadata.Get(exif.FieldName(tag))
+	if err != nil {
+		return "", fmt.Errorf("%s has no %s EXIF tag: %w", path, tag, err)
+	}
+	return value.String(), nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsTemplatedDescription(t *testing.T) {
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"description.md.tmpl", true},
+		{"description.tmpl.md", true},
+		{"description.md", false},
+		{"description.rst", false},
+	}
+	for _, c := range cases {
+		if got := IsTemplatedDescription(c.path); got != c.want {
+			t.Errorf("IsTemplatedDescription(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestRenderDescriptionTemplateIncludeListMediaAndEmbed(t *testing.T) {
+	directory := t.TempDir()
+	mustWriteFile(t, filepath.Join(directory, "intro.md"), "Hello from an include.")
+	mustWriteFile(t, filepath.Join(directory, "photo.jpg"), "not really a jpeg")
+	mustWriteFile(t, filepath.Join(directory, "notes.txt"), "ignored by the *.jpg glob")
+
+	templatePath := filepath.Join(directory, "description.md.tmpl")
+	raw := `{{include "intro.md"}}
+
+{{range listMedia "*.jpg"}}{{embed .}}
+{{end}}`
+	mustWriteFile(t, templatePath, raw)
+
+	rendered, err := RenderDescriptionTemplate(templatePath, raw)
+	if err != nil {
+		t.Fatalf("RenderDescriptionTemplate: %v", err)
+	}
+	if !strings.Contains(rendered, "Hello from an include.") {
+		t.Errorf("rendered = %q, want it to contain the included file's content", rendered)
+	}
+	if !strings.Contains(rendered, "![](photo.jpg)") {
+		t.Errorf("rendered = %q, want it to contain an embed of photo.jpg", rendered)
+	}
+	if strings.Contains(rendered, "notes.txt") {
+		t.Errorf("rendered = %q, want notes.txt excluded by the *.jpg glob", rendered)
+	}
+}
+
+func TestRenderDescriptionTemplateRejectsPathEscape(t *testing.T) {
+	directory := t.TempDir()
+	templatePath := filepath.Join(directory, "description.md.tmpl")
+
+	cases := []struct {
+		name string
+		raw  string
+	}{
+		{"include", `{{include "../../../etc/passwd"}}`},
+		{"listMedia", `{{listMedia "../../../**"}}`},
+		{"exif", `{{exif "../../../etc/passwd" "DateTimeOriginal"}}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := RenderDescriptionTemplate(templatePath, c.raw); err == nil {
+				t.Errorf("RenderDescriptionTemplate(%q) = nil error, want an error rejecting the path escape", c.raw)
+			}
+		})
+	}
+}
+
+func TestLoadDescriptionRendersTemplatedFile(t *testing.T) {
+	directory := t.TempDir()
+	templatePath := filepath.Join(directory, "description.md.tmpl")
+	mustWriteFile(t, templatePath, "Hello, {{.Directory}}.")
+
+	rendered, err := LoadDescription(RunContext{}, templatePath)
+	if err != nil {
+		t.Fatalf("LoadDescription: %v", err)
+	}
+	if !strings.HasPrefix(rendered, "Hello, ") {
+		t.Errorf("LoadDescription rendered = %q, want it to start with %q", rendered, "Hello, ")
+	}
+}
+
+func TestLoadDescriptionPassesThroughStaticFile(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "description.md")
+	mustWriteFile(t, path, "# Title\n\nNot a template.\n")
+
+	raw, err := LoadDescription(RunContext{}, path)
+	if err != nil {
+		t.Fatalf("LoadDescription: %v", err)
+	}
+	if raw != "# Title\n\nNot a template.\n" {
+		t.Errorf("LoadDescription = %q, want the file's raw content unchanged", raw)
+	}
+}
+
+func mustWriteFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
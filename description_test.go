@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+// TestParseDescriptionBlockquoteParagraphsSurvive regression-tests collectParagraphsAndIframes:
+// a <p> nested inside a block container (here, a Markdown blockquote) must still be picked up
+// as a Paragraph instead of being silently dropped.
+func TestParseDescriptionBlockquoteParagraphsSurvive(t *testing.T) {
+	raw := "Intro.\n\n> Quoted text.\n\nOutro.\n"
+	parsed := ParseDescription(RunContext{}, "description.md", raw)
+
+	paragraphs := parsed.Paragraphs["default"]
+	if len(paragraphs) != 3 {
+		t.Fatalf("Paragraphs = %+v, want 3 (Intro., Quoted text., Outro.)", paragraphs)
+	}
+	want := []string{"Intro.", "Quoted text.", "Outro."}
+	for i, content := range want {
+		if paragraphs[i].Content != content {
+			t.Errorf("Paragraphs[%d].Content = %q, want %q", i, paragraphs[i].Content, content)
+		}
+	}
+}
+
+func TestParseDescriptionIframeAmongParagraphsInOrder(t *testing.T) {
+	raw := "Intro.\n\n.iframe https://example.com 480x320\n\nOutro.\n"
+	parsed := ParseDescription(RunContext{}, "description.md", raw)
+
+	paragraphs := parsed.Paragraphs["default"]
+	if len(paragraphs) != 2 || paragraphs[0].Content != "Intro." || paragraphs[1].Content != "Outro." {
+		t.Fatalf("Paragraphs = %+v, want [Intro., Outro.] with the iframe extracted separately", paragraphs)
+	}
+	iframes := parsed.IframeEmbeds["default"]
+	if len(iframes) != 1 || iframes[0].Source != "https://example.com" {
+		t.Fatalf("IframeEmbeds = %+v, want one iframe embedding https://example.com", iframes)
+	}
+}
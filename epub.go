@@ -0,0 +1,282 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// EPUBOptions configures BuildEPUB.
+type EPUBOptions struct {
+	Language   string // The language to export; required, BuildEPUB returns an error when it's empty.
+	CoverImage []byte
+	CoverMIME  string
+	Stylesheet string // Raw CSS, embedded as OEBPS/style.css and linked from every chapter.
+}
+
+// epubManifestItem is one <item> of content.opf's manifest.
+type epubManifestItem struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+}
+
+// BuildEPUB renders db into a single, single-language EPUB3 archive: one chapter per work in
+// opts.Language, with that work's media copied into OEBPS/media/ and its footnotes turned
+// into EPUB popup footnotes. Call it once per language (see epubLanguages) to get the "one
+// .epub per configured language" output the export command produces.
+func BuildEPUB(db []Work, opts EPUBOptions) ([]byte, error) {
+	if opts.Language == "" {
+		return nil, fmt.Errorf("EPUBOptions.Language is required: BuildEPUB produces a single-language EPUB, call it once per language")
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	// mimetype must be the first entry, stored (not deflated), per the EPUB spec.
+	mimetypeWriter, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, fmt.Errorf("writing mimetype: %w", err)
+	}
+	if _, err := mimetypeWriter.Write([]byte("application/epub+zip")); err != nil {
+		return nil, fmt.Errorf("writing mimetype: %w", err)
+	}
+
+	if err := writeZipFile(archive, "META-INF/container.xml", epubContainerXML); err != nil {
+		return nil, err
+	}
+
+	manifest := make([]epubManifestItem, 0)
+	spine := make([]string, 0)
+	navEntries := make([]string, 0)
+	copiedMedia := make(map[string]bool) // Archive href already written to, so repeated sources aren't copied twice.
+
+	if len(opts.CoverImage) > 0 {
+		coverHref := "media/cover" + epubExtensionFromMIME(opts.CoverMIME)
+		if err := writeZipBytes(archive, "OEBPS/"+coverHref, opts.CoverImage); err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, epubManifestItem{ID: "cover-image", Href: coverHref, MediaType: opts.CoverMIME, Properties: "cover-image"})
+	}
+
+	if opts.Stylesheet != "" {
+		if err := writeZipFile(archive, "OEBPS/style.css", opts.Stylesheet); err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, epubManifestItem{ID: "stylesheet", Href: "style.css", MediaType: "text/css"})
+	}
+
+	for workIndex, work := range db {
+		chapterID := fmt.Sprintf("work-%d", workIndex)
+		chapterHref := chapterID + ".xhtml"
+		if err := writeZipFile(archive, "OEBPS/"+chapterHref, renderEPUBChapter(work, opts.Language, opts)); err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, epubManifestItem{ID: chapterID, Href: chapterHref, MediaType: "application/xhtml+xml"})
+		spine = append(spine, chapterID)
+		navEntries = append(navEntries, fmt.Sprintf(`<li><a href="%s">%s</a></li>`, chapterHref, html.EscapeString(work.Title[opts.Language])))
+
+		for _, media := range work.Media[opts.Language] {
+			// Prefix with chapterID: two different works very commonly have same-named
+			// media (e.g. both have a cover.jpg) at different source paths, and a bare
+			// basename would clobber one's bytes and produce a duplicate manifest id.
+			mediaHref := fmt.Sprintf("media/%s-%s", chapterID, path.Base(media.Source))
+			if copiedMedia[mediaHref] {
+				continue
+			}
+			content, err := os.ReadFile(media.Source)
+			if err != nil {
+				continue // Best-effort: a missing/unreadable media file shouldn't abort the whole export.
+			}
+			if err := writeZipBytes(archive, "OEBPS/"+mediaHref, content); err != nil {
+				continue
+			}
+			copiedMedia[mediaHref] = true
+			manifest = append(manifest, epubManifestItem{
+				ID:        "media-" + UnicodeSlug(mediaHref),
+				Href:      mediaHref,
+				MediaType: media.ContentType,
+			})
+		}
+	}
+
+	if err := writeZipFile(archive, "OEBPS/nav.xhtml", renderEPUBNav(navEntries)); err != nil {
+		return nil, err
+	}
+	manifest = append(manifest, epubManifestItem{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"})
+
+	if err := writeZipFile(archive, "OEBPS/toc.ncx", renderEPUBTocNCX(spine, db, opts.Language)); err != nil {
+		return nil, err
+	}
+	manifest = append(manifest, epubManifestItem{ID: "ncx", Href: "toc.ncx", MediaType: "application/x-dtbncx+xml"})
+
+	if err := writeZipFile(archive, "OEBPS/content.opf", renderEPUBContentOPF(manifest, spine, opts)); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("finalizing epub archive: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// epubLanguages returns the languages to export: just `only` when set, otherwise every
+// language found across db, in a stable (sorted) order.
+func epubLanguages(db []Work, only string) []string {
+	if only != "" {
+		return []string{only}
+	}
+	seen := make(map[string]bool)
+	languages := make([]string, 0)
+	for _, work := range db {
+		for language := range work.Title {
+			if !seen[language] {
+				seen[language] = true
+				languages = append(languages, language)
+			}
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func writeZipFile(archive *zip.Writer, name string, content string) error {
+	return writeZipBytes(archive, name, []byte(content))
+}
+
+func writeZipBytes(archive *zip.Writer, name string, content []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", name, err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+func epubExtensionFromMIME(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".img"
+	}
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// renderEPUBChapter renders one work, in one language, as an XHTML chapter: title, paragraphs
+// (with <abbr> already inlined by processParagraph) and footnotes as EPUB popup footnotes.
+func renderEPUBChapter(work Work, language string, opts EPUBOptions) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "<h1>%s</h1>\n", html.EscapeString(work.Title[language]))
+	for _, paragraph := range work.Paragraphs[language] {
+		fmt.Fprintf(&body, `<p id="%s">%s</p>`+"\n", paragraph.ID, paragraph.Content)
+	}
+	if footnotes := work.Footnotes[language]; len(footnotes) > 0 {
+		body.WriteString(`<aside epub:type="footnotes">` + "\n")
+		for _, footnote := range footnotes {
+			fmt.Fprintf(&body, `<p id="fn:%s" epub:type="footnote">%s</p>`+"\n", footnote.Name, footnote.Content)
+		}
+		body.WriteString("</aside>\n")
+	}
+	stylesheetLink := ""
+	if opts.Stylesheet != "" {
+		stylesheetLink = `<link rel="stylesheet" type="text/css" href="style.css"/>` + "\n"
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>%s</title>
+  %s</head>
+<body>
+%s</body>
+</html>
+`, html.EscapeString(work.Title[language]), stylesheetLink, body.String())
+}
+
+// renderEPUBNav renders the EPUB3 nav.xhtml document, the required navigation document.
+func renderEPUBNav(entries []string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>Table of Contents</title></head>
+<body>
+  <nav epub:type="toc">
+    <ol>
+      %s
+    </ol>
+  </nav>
+</body>
+</html>
+`, strings.Join(entries, "\n      "))
+}
+
+// renderEPUBTocNCX renders toc.ncx, kept alongside nav.xhtml for EPUB2 reader compatibility.
+func renderEPUBTocNCX(spine []string, db []Work, language string) string {
+	var navPoints strings.Builder
+	for i, chapterID := range spine {
+		work := db[i]
+		fmt.Fprintf(&navPoints, `<navPoint id="%s" playOrder="%d"><navLabel><text>%s</text></navLabel><content src="%s.xhtml"/></navPoint>`+"\n",
+			chapterID, i+1, html.EscapeString(work.Title[language]), chapterID)
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head></head>
+  <docTitle><text>Portfolio</text></docTitle>
+  <navMap>
+    %s
+  </navMap>
+</ncx>
+`, navPoints.String())
+}
+
+// renderEPUBContentOPF renders content.opf, the EPUB package document: the manifest of every
+// resource (with MIME types derived from the existing media analyzer) and the spine order.
+func renderEPUBContentOPF(manifest []epubManifestItem, spine []string, opts EPUBOptions) string {
+	var manifestItems, spineItems strings.Builder
+	for _, item := range manifest {
+		properties := ""
+		if item.Properties != "" {
+			properties = fmt.Sprintf(` properties="%s"`, item.Properties)
+		}
+		fmt.Fprintf(&manifestItems, `<item id="%s" href="%s" media-type="%s"%s/>`+"\n", item.ID, item.Href, item.MediaType, properties)
+	}
+	for _, chapterID := range spine {
+		fmt.Fprintf(&spineItems, `<itemref idref="%s"/>`+"\n", chapterID)
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">portfoliodb-export</dc:identifier>
+    <dc:title>Portfolio</dc:title>
+    <dc:language>%s</dc:language>
+    <meta property="dcterms:modified">1970-01-01T00:00:00Z</meta>
+  </metadata>
+  <manifest>
+    %s
+  </manifest>
+  <spine>
+    %s
+  </spine>
+</package>
+`, opts.Language, manifestItems.String(), spineItems.String())
+}
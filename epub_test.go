@@ -0,0 +1,117 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEpubOutputPathForLanguage(t *testing.T) {
+	cases := []struct {
+		name, base, language, want string
+	}{
+		{"default output name", "portfolio.epub", "fr", "portfolio.fr.epub"},
+		{"nested path", "out/portfolio.epub", "en", "out/portfolio.en.epub"},
+		{"missing extension is appended after", "portfolio", "de", "portfolio.de"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := epubOutputPathForLanguage(c.base, c.language); got != c.want {
+				t.Errorf("epubOutputPathForLanguage(%q, %q) = %q, want %q", c.base, c.language, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEpubLanguages(t *testing.T) {
+	db := []Work{
+		{Title: map[string]string{"en": "Hello", "fr": "Bonjour"}},
+		{Title: map[string]string{"en": "World"}},
+	}
+
+	if got := epubLanguages(db, "fr"); len(got) != 1 || got[0] != "fr" {
+		t.Errorf("epubLanguages(db, %q) = %v, want [%q]", "fr", got, "fr")
+	}
+
+	got := epubLanguages(db, "")
+	want := []string{"en", "fr"}
+	if len(got) != len(want) {
+		t.Fatalf("epubLanguages(db, \"\") = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("epubLanguages(db, \"\")[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBuildEPUBMediaHrefsDontCollideAcrossWorks regression-tests the fix for two different
+// works that both reference a same-named media file (e.g. cover.jpg) at different source
+// paths: their archive hrefs (and hence manifest ids) must stay distinct, or the OPF ends up
+// with duplicate ids and one work's bytes silently clobber the other's.
+func TestBuildEPUBMediaHrefsDontCollideAcrossWorks(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	pathA := filepath.Join(dirA, "cover.jpg")
+	pathB := filepath.Join(dirB, "cover.jpg")
+	if err := os.WriteFile(pathA, []byte("image A"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathB, []byte("image B"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := []Work{
+		{
+			Title:      map[string]string{"en": "Work A"},
+			Paragraphs: map[string][]Paragraph{"en": nil},
+			Media:      map[string][]Media{"en": {{Source: pathA, ContentType: "image/jpeg"}}},
+		},
+		{
+			Title:      map[string]string{"en": "Work B"},
+			Paragraphs: map[string][]Paragraph{"en": nil},
+			Media:      map[string][]Media{"en": {{Source: pathB, ContentType: "image/jpeg"}}},
+		},
+	}
+
+	epub, err := BuildEPUB(db, EPUBOptions{Language: "en"})
+	if err != nil {
+		t.Fatalf("BuildEPUB: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(epub), int64(len(epub)))
+	if err != nil {
+		t.Fatalf("reading generated epub as zip: %v", err)
+	}
+
+	contents := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		content := make([]byte, file.UncompressedSize64)
+		if _, err := reader.Read(content); err != nil && file.UncompressedSize64 > 0 {
+			t.Fatalf("reading %s: %v", file.Name, err)
+		}
+		reader.Close()
+		contents[file.Name] = content
+	}
+
+	hrefA := "OEBPS/media/work-0-cover.jpg"
+	hrefB := "OEBPS/media/work-1-cover.jpg"
+	if string(contents[hrefA]) != "image A" {
+		t.Errorf("contents[%q] = %q, want %q", hrefA, contents[hrefA], "image A")
+	}
+	if string(contents[hrefB]) != "image B" {
+		t.Errorf("contents[%q] = %q, want %q", hrefB, contents[hrefB], "image B")
+	}
+}
+
+func TestBuildEPUBRequiresLanguage(t *testing.T) {
+	if _, err := BuildEPUB(nil, EPUBOptions{}); err == nil {
+		t.Error("BuildEPUB(nil, EPUBOptions{}) with no Language should return an error")
+	}
+}
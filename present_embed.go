@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// handlePresentStyleDirectives rewrites golang.org/x/tools/present-inspired directive lines
+// (.video, .iframe, .caption) into raw HTML placeholders, before gomarkdown runs. The soup
+// walker in parseSingleLanguageDescription then lifts those placeholders into typed
+// MediaEmbedDeclaration/IframeEmbed structs, same as it does for plain ![]() images: gomarkdown
+// passes inline/block HTML through untouched, so this is the same trick handleAltMediaEmbedSyntax
+// uses for the >[...](...) syntax.
+func handlePresentStyleDirectives(markdownRaw string) string {
+	markdownRaw = presentVideoDirectivePattern.ReplaceAllStringFunc(markdownRaw, func(match string) string {
+		groups := presentVideoDirectivePattern.FindStringSubmatch(match)
+		src, start, end := groups[1], groups[2], groups[3]
+		if start == "" && end == "" {
+			return fmt.Sprintf(`<img src=%q data-video="1">`, src)
+		}
+		return fmt.Sprintf(`<img src=%q data-video="1" data-t-start=%q data-t-end=%q>`, src, start, end)
+	})
+	markdownRaw = presentIframeDirectivePattern.ReplaceAllStringFunc(markdownRaw, func(match string) string {
+		groups := presentIframeDirectivePattern.FindStringSubmatch(match)
+		src, width, height, sandbox := groups[1], groups[2], groups[3], groups[4]
+		return fmt.Sprintf(`<iframe src=%q width=%q height=%q data-sandbox=%q></iframe>`, src, width, height, sandbox)
+	})
+	markdownRaw = presentCaptionDirectivePattern.ReplaceAllString(markdownRaw, `<p data-caption="1">$1</p>`)
+	return markdownRaw
+}
+
+var (
+	// .video path/to/file.mp4#t=30,90
+	// Note: [ \t]*$ (not \s*$) deliberately stops at the end of the line — \s matches \n,
+	// so \s*$ would also swallow the blank line separating the directive from whatever
+	// comes after it, merging them into the same gomarkdown paragraph.
+	presentVideoDirectivePattern = regexp.MustCompile(`(?m)^\.video\s+(\S+?)(?:#t=(\d+),(\d+))?[ \t]*$`)
+	// .iframe https://example.com 480x320 [sandbox=allow-scripts]
+	presentIframeDirectivePattern = regexp.MustCompile(`(?m)^\.iframe\s+(\S+)\s+(\d+)x(\d+)(?:\s+sandbox=(\S+))?[ \t]*$`)
+	// .caption Some caption text
+	presentCaptionDirectivePattern = regexp.MustCompile(`(?m)^\.caption\s+(.+)$`)
+)
+
+// parseVideoTimeRange parses the start/end fragments captured from a `.video path#t=30,90`
+// directive. -1 means "unset", matching what MediaEmbedDeclaration's StartTime/EndTime hold
+// when no time range was given.
+func parseVideoTimeRange(startRaw string, endRaw string) (startTime int, endTime int) {
+	startTime, endTime = -1, -1
+	if parsed, err := strconv.Atoi(startRaw); err == nil {
+		startTime = parsed
+	}
+	if parsed, err := strconv.Atoi(endRaw); err == nil {
+		endTime = parsed
+	}
+	return startTime, endTime
+}
+
+// parseIframeDimensions parses the width/height captured from a `.iframe url WIDTHxHEIGHT` directive.
+func parseIframeDimensions(widthRaw string, heightRaw string) (width int, height int) {
+	width, _ = strconv.Atoi(widthRaw)
+	height, _ = strconv.Atoi(heightRaw)
+	return width, height
+}
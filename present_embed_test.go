@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestHandlePresentStyleDirectives(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"video with time range",
+			".video clip.mp4#t=30,90\n",
+			`<img src="clip.mp4" data-video="1" data-t-start="30" data-t-end="90">` + "\n",
+		},
+		{
+			"video without time range",
+			".video clip.mp4\n",
+			`<img src="clip.mp4" data-video="1">` + "\n",
+		},
+		{
+			"iframe with sandbox",
+			".iframe https://example.com 480x320 sandbox=allow-scripts\n",
+			`<iframe src="https://example.com" width="480" height="320" data-sandbox="allow-scripts"></iframe>` + "\n",
+		},
+		{
+			"caption",
+			".caption A nice caption\n",
+			`<p data-caption="1">A nice caption</p>` + "\n",
+		},
+		{
+			// Regression test: a trailing \s*$ (rather than [ \t]*$) would consume the blank
+			// line below the directive, merging "Outro." into the same gomarkdown paragraph
+			// as the placeholder and breaking the childrenCount == 1 check downstream.
+			"video directive preserves the following blank line",
+			"Intro.\n\n.video clip.mp4#t=30,90\n\nOutro.\n",
+			"Intro.\n\n" + `<img src="clip.mp4" data-video="1" data-t-start="30" data-t-end="90">` + "\n\nOutro.\n",
+		},
+		{
+			"iframe directive preserves the following blank line",
+			"Intro.\n\n.iframe https://example.com 480x320\n\nOutro.\n",
+			"Intro.\n\n" + `<iframe src="https://example.com" width="480" height="320" data-sandbox=""></iframe>` + "\n\nOutro.\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := handlePresentStyleDirectives(c.in); got != c.want {
+				t.Errorf("handlePresentStyleDirectives(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseVideoTimeRange(t *testing.T) {
+	cases := []struct {
+		name               string
+		startRaw, endRaw   string
+		wantStart, wantEnd int
+	}{
+		{"both set", "30", "90", 30, 90},
+		{"unset", "", "", -1, -1},
+		{"only start", "10", "", 10, -1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			start, end := parseVideoTimeRange(c.startRaw, c.endRaw)
+			if start != c.wantStart || end != c.wantEnd {
+				t.Errorf("parseVideoTimeRange(%q, %q) = (%d, %d), want (%d, %d)",
+					c.startRaw, c.endRaw, start, end, c.wantStart, c.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseIframeDimensions(t *testing.T) {
+	width, height := parseIframeDimensions("480", "320")
+	if width != 480 || height != 320 {
+		t.Errorf("parseIframeDimensions(480, 320) = (%d, %d), want (480, 320)", width, height)
+	}
+}
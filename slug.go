@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripHTMLTags removes any HTML tags from s, leaving only their text content.
+func stripHTMLTags(s string) string {
+	return htmlTagPattern.ReplaceAllString(s, "")
+}
+
+// UnicodeSlug turns s into an anchor-safe identifier without discarding non-Latin scripts.
+// Runes in the Letter, Mark, Number and Symbol Unicode categories are kept as-is (case is
+// preserved), while any run of Punctuation, Separator or Other runes is collapsed into a
+// single "-". This is unlike github.com/metal3d/go-slugify, which strips non-Latin
+// characters entirely, turning e.g. a Japanese or Arabic title into an empty slug.
+func UnicodeSlug(s string) string {
+	s = stripHTMLTags(s)
+	var out strings.Builder
+	inGap := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r) || unicode.IsSymbol(r) {
+			out.WriteRune(r)
+			inGap = false
+		} else if !inGap {
+			out.WriteRune('-')
+			inGap = true
+		}
+	}
+	return strings.Trim(out.String(), "-")
+}
+
+// deduplicateSlug returns slug unchanged the first time it's seen (according to seen), or
+// appends -2, -3, ... on subsequent collisions. seen is mutated and should be reused across
+// every slug produced within the same language, so that IDs stay unique within it.
+func deduplicateSlug(slug string, seen map[string]int) string {
+	seen[slug]++
+	if seen[slug] == 1 {
+		return slug
+	}
+	return slug + "-" + strconv.Itoa(seen[slug])
+}
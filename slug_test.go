@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestUnicodeSlug(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ascii title", "Hello, World!", "Hello-World"},
+		{"japanese title", "こんにちは世界", "こんにちは世界"},
+		{"arabic title", "مرحبا بالعالم", "مرحبا-بالعالم"},
+		{"strips html tags", "a <em>nice</em> <code>title</code>", "a-nice-title"},
+		{"collapses punctuation/space runs", "foo   --  bar", "foo-bar"},
+		{"trims leading and trailing separators", "  .foo bar.  ", "foo-bar"},
+		{"keeps numbers and symbols", "v2.0 100+ done", "v2-0-100+-done"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := UnicodeSlug(c.in); got != c.want {
+				t.Errorf("UnicodeSlug(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestDeduplicateSlug(t *testing.T) {
+	seen := make(map[string]int)
+	got := []string{
+		deduplicateSlug("intro", seen),
+		deduplicateSlug("intro", seen),
+		deduplicateSlug("intro", seen),
+		deduplicateSlug("outro", seen),
+	}
+	want := []string{"intro", "intro-2", "intro-3", "outro"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("deduplicateSlug call #%d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}